@@ -0,0 +1,183 @@
+// Copyright (c) 2020 Prodefi -  D FROZEN SOFT PRIVATE LIMITED
+
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+)
+
+// PreSignature is a Schnorr adaptor (pre-)signature: a signature that
+// verifies against an adaptor point T instead of the identity, and that
+// only becomes a valid Signature once the secret behind T is known.
+type PreSignature struct {
+	RPrime CurvePoint `json:"rPrime"`
+	SPrime *big.Int   `json:"sPrime"`
+}
+
+// Signature is an ordinary Schnorr signature: s·G == R + H(R||P||m)·P.
+type Signature struct {
+	R CurvePoint `json:"r"`
+	S *big.Int   `json:"s"`
+}
+
+// schnorrChallenge computes H(R||P||m), the Fiat-Shamir challenge shared by
+// PreSign and PreVerify (and the one an adapted Signature verifies under).
+func schnorrChallenge(r *CurvePoint, p *CurvePoint, msg []byte) *big.Int {
+	h := sha256.New()
+	h.Write(r.Marshal())
+	h.Write(p.Marshal())
+	h.Write(msg)
+	e := new(big.Int).SetBytes(h.Sum(nil))
+	return e.Mod(e, CurvePoint{}.Order())
+}
+
+// PreSign produces a Schnorr adaptor signature for msg under sk, tied to
+// the adaptor point T. The result verifies with PreVerify against P =
+// derivePublicKey(sk) and T, but is not itself a valid signature until
+// Adapt is called with the discrete log of T.
+//
+//   R' = k·G + T
+//   s' = k + H(R'||P||m)·sk
+//
+func PreSign(sk *big.Int, msg []byte, T *CurvePoint) (*PreSignature, error) {
+	if false == isValidSecretKey(sk) {
+		return nil, fmt.Errorf("Invalid secret key: %v", sk)
+	}
+	if nil == T {
+		return nil, fmt.Errorf("Null adaptor point provided")
+	}
+
+	N := CurvePoint{}.Order()
+
+	k := CurvePoint{}.RandomN()
+	rPrime := CurvePoint{}.ScalarBaseMult(k).Add(*T)
+
+	p := derivePublicKey(sk)
+	e := schnorrChallenge(&rPrime, &p, msg)
+
+	sPrime := new(big.Int).Mul(e, sk)
+	sPrime.Add(sPrime, k)
+	sPrime.Mod(sPrime, N)
+
+	return &PreSignature{rPrime, sPrime}, nil
+}
+
+// PreVerify checks that ps is a valid adaptor signature by P over msg for
+// the adaptor point T, i.e. that s'·G + T == R' + H(R'||P||m)·P.
+func PreVerify(P, T *CurvePoint, msg []byte, ps *PreSignature) bool {
+	if nil == P || nil == T || nil == ps {
+		return false
+	}
+
+	e := schnorrChallenge(&ps.RPrime, P, msg)
+
+	lhs := CurvePoint{}.ScalarBaseMult(ps.SPrime).Add(*T)
+	rhs := ps.RPrime.Add(P.ScalarMult(e))
+
+	return string(lhs.Marshal()) == string(rhs.Marshal())
+}
+
+// Adapt completes a pre-signature into a valid Signature using the
+// discrete log t of the adaptor point T that was used in PreSign:
+//
+//   s = s' + t
+//   R = R' = k·G + T  (T is already folded into ps.RPrime)
+//
+func Adapt(ps *PreSignature, t *big.Int) *Signature {
+	if nil == ps {
+		return nil
+	}
+
+	s := new(big.Int).Add(ps.SPrime, t)
+	s.Mod(s, CurvePoint{}.Order())
+
+	return &Signature{ps.RPrime, s}
+}
+
+// Extract recovers the adaptor secret t from a completed Signature and the
+// PreSignature it was adapted from: t = s - s'. This is the other half of
+// the atomic-swap property - whoever publishes sig reveals t to anyone
+// still holding ps.
+func Extract(ps *PreSignature, sig *Signature) *big.Int {
+	if nil == ps || nil == sig {
+		return nil
+	}
+
+	t := new(big.Int).Sub(sig.S, ps.SPrime)
+	t.Mod(t, CurvePoint{}.Order())
+	return t
+}
+
+// DLEQProof is a Fiat-Shamir proof that the same secret x sits behind
+// Y1 = x·G1 and Y2 = x·G2, for two (possibly different) generators.
+type DLEQProof struct {
+	A1 CurvePoint `json:"a1"`
+	A2 CurvePoint `json:"a2"`
+	Z  *big.Int   `json:"z"`
+}
+
+// dleqChallenge hashes both generators, both public points, and both
+// commitments into a single Fiat-Shamir challenge scalar.
+func dleqChallenge(G1, G2, Y1, Y2, A1, A2 *CurvePoint) *big.Int {
+	h := sha256.New()
+	for _, p := range []*CurvePoint{G1, G2, Y1, Y2, A1, A2} {
+		h.Write(p.Marshal())
+	}
+	e := new(big.Int).SetBytes(h.Sum(nil))
+	return e.Mod(e, CurvePoint{}.Order())
+}
+
+// DLEQProve proves knowledge of x such that Y1 = x·G1 and Y2 = x·G2,
+// without revealing x. This is what lets a swap counterparty confirm that
+// the same secret backs an adaptor point on one curve context and a
+// pre-signature on another, before they commit funds.
+func DLEQProve(x *big.Int, G1, G2 *CurvePoint) (*DLEQProof, error) {
+	if false == isValidSecretKey(x) {
+		return nil, fmt.Errorf("Invalid secret key: %v", x)
+	}
+	if nil == G1 || nil == G2 {
+		return nil, fmt.Errorf("Null generator provided")
+	}
+
+	N := CurvePoint{}.Order()
+
+	Y1 := G1.ScalarMult(x)
+	Y2 := G2.ScalarMult(x)
+
+	k := CurvePoint{}.RandomN()
+	A1 := G1.ScalarMult(k)
+	A2 := G2.ScalarMult(k)
+
+	e := dleqChallenge(G1, G2, &Y1, &Y2, &A1, &A2)
+
+	z := new(big.Int).Mul(e, x)
+	z.Add(z, k)
+	z.Mod(z, N)
+
+	return &DLEQProof{A1, A2, z}, nil
+}
+
+// DLEQVerify checks a DLEQProof that Y1 and Y2 (given generators G1, G2)
+// share the same discrete log:
+//
+//   z·G1 == A1 + e·Y1
+//   z·G2 == A2 + e·Y2
+//
+func DLEQVerify(G1, G2, Y1, Y2 *CurvePoint, proof *DLEQProof) bool {
+	if nil == G1 || nil == G2 || nil == Y1 || nil == Y2 || nil == proof {
+		return false
+	}
+
+	e := dleqChallenge(G1, G2, Y1, Y2, &proof.A1, &proof.A2)
+
+	lhs1 := G1.ScalarMult(proof.Z)
+	rhs1 := proof.A1.Add(Y1.ScalarMult(e))
+
+	lhs2 := G2.ScalarMult(proof.Z)
+	rhs2 := proof.A2.Add(Y2.ScalarMult(e))
+
+	return string(lhs1.Marshal()) == string(rhs1.Marshal()) &&
+		string(lhs2.Marshal()) == string(rhs2.Marshal())
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2020 Prodefi -  D FROZEN SOFT PRIVATE LIMITED
+
+package main
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestPreSignAdaptExtractRoundTrip(t *testing.T) {
+	sk := CurvePoint{}.RandomN()
+	p := derivePublicKey(sk)
+
+	tSecret := CurvePoint{}.RandomN()
+	T := derivePublicKey(tSecret)
+
+	msg := []byte("atomic swap payload")
+
+	ps, err := PreSign(sk, msg, &T)
+	if err != nil {
+		t.Fatalf("PreSign: %v", err)
+	}
+
+	if !PreVerify(&p, &T, msg, ps) {
+		t.Fatalf("PreVerify rejected a genuine pre-signature")
+	}
+
+	sig := Adapt(ps, tSecret)
+
+	extracted := Extract(ps, sig)
+	if extracted.Cmp(tSecret) != 0 {
+		t.Fatalf("Extract = %v, want %v", extracted, tSecret)
+	}
+}
+
+func TestPreVerifyRejectsWrongAdaptorPoint(t *testing.T) {
+	sk := CurvePoint{}.RandomN()
+	p := derivePublicKey(sk)
+
+	T := derivePublicKey(CurvePoint{}.RandomN())
+	wrongT := derivePublicKey(CurvePoint{}.RandomN())
+
+	msg := []byte("atomic swap payload")
+
+	ps, err := PreSign(sk, msg, &T)
+	if err != nil {
+		t.Fatalf("PreSign: %v", err)
+	}
+
+	if PreVerify(&p, &wrongT, msg, ps) {
+		t.Fatalf("PreVerify accepted a pre-signature against the wrong adaptor point")
+	}
+}
+
+func TestDLEQProveVerifyRoundTrip(t *testing.T) {
+	x := CurvePoint{}.RandomN()
+	G1 := CurvePoint{}.ScalarBaseMult(big.NewInt(1))
+	G2 := CurvePoint{}.ScalarBaseMult(big.NewInt(2))
+
+	proof, err := DLEQProve(x, &G1, &G2)
+	if err != nil {
+		t.Fatalf("DLEQProve: %v", err)
+	}
+
+	Y1 := G1.ScalarMult(x)
+	Y2 := G2.ScalarMult(x)
+
+	if !DLEQVerify(&G1, &G2, &Y1, &Y2, proof) {
+		t.Fatalf("DLEQVerify rejected a genuine proof")
+	}
+}
+
+func TestDLEQVerifyRejectsMismatchedSecrets(t *testing.T) {
+	x := CurvePoint{}.RandomN()
+	y := CurvePoint{}.RandomN()
+	G1 := CurvePoint{}.ScalarBaseMult(big.NewInt(1))
+	G2 := CurvePoint{}.ScalarBaseMult(big.NewInt(2))
+
+	proof, err := DLEQProve(x, &G1, &G2)
+	if err != nil {
+		t.Fatalf("DLEQProve: %v", err)
+	}
+
+	Y1 := G1.ScalarMult(x)
+	Y2 := G2.ScalarMult(y) // different secret behind Y2
+
+	if DLEQVerify(&G1, &G2, &Y1, &Y2, proof) {
+		t.Fatalf("DLEQVerify accepted a proof for mismatched secrets")
+	}
+}
@@ -0,0 +1,218 @@
+// Copyright (c) 2020 Prodefi -  D FROZEN SOFT PRIVATE LIMITED
+
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	armorVersion = "1"
+	argon2idName = "argon2id"
+
+	armorSaltSize  = 16
+	armorNonceSize = 12
+	aesKeySize     = 32
+
+	argon2Time    = 1
+	argon2Memory  = 64 * 1024
+	argon2Threads = 4
+
+	armorLineWidth = 64
+)
+
+// SaveEncrypted writes s to w as a passphrase-encrypted, ASCII-armored
+// envelope - the missing safe way to persist the very sensitive
+// MyAddresses private keys, which today only round-trip
+// through plain JSON.
+func (s *StealthSession) SaveEncrypted(w io.Writer, passphrase []byte) error {
+	plaintext, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return encryptAndArmor(w, "SESSION", passphrase, plaintext)
+}
+
+// LoadEncrypted reads and decrypts an envelope written by SaveEncrypted
+// into s.
+func (s *StealthSession) LoadEncrypted(r io.Reader, passphrase []byte) error {
+	plaintext, err := dearmorAndDecrypt(r, "SESSION", passphrase)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(plaintext, s)
+}
+
+// SaveEncrypted writes r to w as a passphrase-encrypted, ASCII-armored
+// envelope, for the same reason as StealthSession.SaveEncrypted: a Ring's
+// PrivKeys are too sensitive to leave as plain JSON on disk.
+func (r *Ring) SaveEncrypted(w io.Writer, passphrase []byte) error {
+	plaintext, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return encryptAndArmor(w, "RING", passphrase, plaintext)
+}
+
+// LoadEncrypted reads and decrypts an envelope written by SaveEncrypted
+// into r.
+func (r *Ring) LoadEncrypted(rd io.Reader, passphrase []byte) error {
+	plaintext, err := dearmorAndDecrypt(rd, "RING", passphrase)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(plaintext, r)
+}
+
+// encryptAndArmor derives a key from passphrase with argon2id, seals
+// plaintext with AES-256-GCM, and writes the result to w as an
+// ASCII-armored envelope bracketed by label.
+//
+//	-----BEGIN APLOUNE <label>-----
+//	Version: 1
+//	KDF: argon2id
+//	Salt: <hex>
+//	Nonce: <hex>
+//
+//	<base64 body>
+//	-----END APLOUNE <label>-----
+func encryptAndArmor(w io.Writer, label string, passphrase []byte, plaintext []byte) error {
+	salt := make([]byte, armorSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+
+	key := argon2.IDKey(passphrase, salt, argon2Time, argon2Memory, argon2Threads, aesKeySize)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, armorNonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	ciphertext := gcm.Seal(nil, nonce, plaintext, nil)
+
+	fmt.Fprintf(w, "-----BEGIN APLOUNE %s-----\n", label)
+	fmt.Fprintf(w, "Version: %s\n", armorVersion)
+	fmt.Fprintf(w, "KDF: %s\n", argon2idName)
+	fmt.Fprintf(w, "Salt: %s\n", hex.EncodeToString(salt))
+	fmt.Fprintf(w, "Nonce: %s\n", hex.EncodeToString(nonce))
+	fmt.Fprintln(w)
+
+	body := base64.StdEncoding.EncodeToString(ciphertext)
+	for len(body) > armorLineWidth {
+		fmt.Fprintln(w, body[:armorLineWidth])
+		body = body[armorLineWidth:]
+	}
+	if len(body) > 0 {
+		fmt.Fprintln(w, body)
+	}
+
+	fmt.Fprintf(w, "-----END APLOUNE %s-----\n", label)
+	return nil
+}
+
+// dearmorAndDecrypt reverses encryptAndArmor: it parses the envelope
+// headers, re-derives the key with the recorded KDF and salt, and opens
+// the AES-256-GCM ciphertext.
+func dearmorAndDecrypt(r io.Reader, label string, passphrase []byte) ([]byte, error) {
+	raw, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	beginLine := fmt.Sprintf("-----BEGIN APLOUNE %s-----", label)
+	endLine := fmt.Sprintf("-----END APLOUNE %s-----", label)
+
+	text := string(raw)
+	start := strings.Index(text, beginLine)
+	end := strings.Index(text, endLine)
+	if start < 0 || end < 0 || end < start {
+		return nil, fmt.Errorf("armor: missing %s envelope", label)
+	}
+
+	headers, bodyLines, err := parseArmorBody(text[start+len(beginLine) : end])
+	if err != nil {
+		return nil, err
+	}
+
+	if headers["Version"] != armorVersion {
+		return nil, fmt.Errorf("armor: unsupported version %q", headers["Version"])
+	}
+	if headers["KDF"] != argon2idName {
+		return nil, fmt.Errorf("armor: unsupported KDF %q", headers["KDF"])
+	}
+
+	salt, err := hex.DecodeString(headers["Salt"])
+	if err != nil {
+		return nil, fmt.Errorf("armor: invalid salt: %w", err)
+	}
+	nonce, err := hex.DecodeString(headers["Nonce"])
+	if err != nil {
+		return nil, fmt.Errorf("armor: invalid nonce: %w", err)
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(strings.Join(bodyLines, ""))
+	if err != nil {
+		return nil, fmt.Errorf("armor: invalid body: %w", err)
+	}
+
+	key := argon2.IDKey(passphrase, salt, argon2Time, argon2Memory, argon2Threads, aesKeySize)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
+
+// parseArmorBody splits the region between an envelope's BEGIN/END lines
+// into its `Key: value` headers and its base64 body lines, which are
+// separated by the first blank line.
+func parseArmorBody(inner string) (headers map[string]string, bodyLines []string, err error) {
+	headers = map[string]string{}
+	inBody := false
+
+	for _, line := range strings.Split(strings.TrimSpace(inner), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			inBody = true
+			continue
+		}
+		if !inBody {
+			parts := strings.SplitN(line, ":", 2)
+			if len(parts) != 2 {
+				return nil, nil, fmt.Errorf("armor: malformed header %q", line)
+			}
+			headers[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+			continue
+		}
+		bodyLines = append(bodyLines, line)
+	}
+
+	return headers, bodyLines, nil
+}
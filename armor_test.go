@@ -0,0 +1,78 @@
+// Copyright (c) 2020 Prodefi -  D FROZEN SOFT PRIVATE LIMITED
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestEncryptAndArmorRoundTrip(t *testing.T) {
+	passphrase := []byte("correct horse battery staple")
+	plaintext := []byte(`{"hello":"world"}`)
+
+	var buf bytes.Buffer
+	if err := encryptAndArmor(&buf, "SESSION", passphrase, plaintext); err != nil {
+		t.Fatalf("encryptAndArmor: %v", err)
+	}
+
+	armored := buf.String()
+	if !strings.Contains(armored, "-----BEGIN APLOUNE SESSION-----") {
+		t.Fatalf("armored output missing BEGIN line: %q", armored)
+	}
+
+	got, err := dearmorAndDecrypt(strings.NewReader(armored), "SESSION", passphrase)
+	if err != nil {
+		t.Fatalf("dearmorAndDecrypt: %v", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Fatalf("dearmorAndDecrypt = %q, want %q", got, plaintext)
+	}
+}
+
+func TestDearmorAndDecryptRejectsWrongPassphrase(t *testing.T) {
+	var buf bytes.Buffer
+	if err := encryptAndArmor(&buf, "RING", []byte("right passphrase"), []byte("secret")); err != nil {
+		t.Fatalf("encryptAndArmor: %v", err)
+	}
+
+	if _, err := dearmorAndDecrypt(&buf, "RING", []byte("wrong passphrase")); err == nil {
+		t.Fatalf("dearmorAndDecrypt accepted the wrong passphrase")
+	}
+}
+
+func TestDearmorAndDecryptRejectsWrongLabel(t *testing.T) {
+	var buf bytes.Buffer
+	if err := encryptAndArmor(&buf, "SESSION", []byte("pw"), []byte("secret")); err != nil {
+		t.Fatalf("encryptAndArmor: %v", err)
+	}
+
+	if _, err := dearmorAndDecrypt(&buf, "RING", []byte("pw")); err == nil {
+		t.Fatalf("dearmorAndDecrypt accepted an envelope under the wrong label")
+	}
+}
+
+func TestDearmorAndDecryptRejectsTamperedBody(t *testing.T) {
+	var buf bytes.Buffer
+	if err := encryptAndArmor(&buf, "SESSION", []byte("pw"), []byte("secret")); err != nil {
+		t.Fatalf("encryptAndArmor: %v", err)
+	}
+
+	armored := buf.String()
+	endLine := "-----END APLOUNE SESSION-----"
+	bodyEnd := strings.Index(armored, endLine)
+	if bodyEnd < 1 {
+		t.Fatalf("could not locate END line in armored output")
+	}
+	// Flip the last base64 character of the body, just before the END line.
+	flipped := byte('A')
+	if armored[bodyEnd-2] == 'A' {
+		flipped = 'B'
+	}
+	tampered := armored[:bodyEnd-2] + string(flipped) + armored[bodyEnd-1:]
+
+	if _, err := dearmorAndDecrypt(strings.NewReader(tampered), "SESSION", []byte("pw")); err == nil {
+		t.Fatalf("dearmorAndDecrypt accepted a tampered envelope")
+	}
+}
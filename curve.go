@@ -0,0 +1,264 @@
+// Copyright (c) 2020 Prodefi -  D FROZEN SOFT PRIVATE LIMITED
+
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha512"
+	"fmt"
+	"math/big"
+
+	"filippo.io/edwards25519"
+	"golang.org/x/crypto/curve25519"
+)
+
+// GroupElement is a point on whichever curve a Group implements. CurvePoint
+// already satisfies this (it has a Marshal() []byte method), so the
+// existing secp256k1 code needs no changes to plug into the abstraction.
+type GroupElement interface {
+	Marshal() []byte
+}
+
+// Group abstracts the elliptic-curve backend used for stealth derivation,
+// ECDH and ring signing, so the same protocol code can run over either the
+// module's original secp256k1-style curve or Ed25519/Curve25519.
+type Group interface {
+	Name() string
+	RandomScalar() *big.Int
+	Order() *big.Int
+	ScalarBaseMult(k *big.Int) GroupElement
+	ScalarMult(p GroupElement, k *big.Int) GroupElement
+	Add(a, b GroupElement) GroupElement
+	HashToPoint(h [32]byte) GroupElement
+	Marshal(p GroupElement) []byte
+	Unmarshal(data []byte) (GroupElement, error)
+}
+
+// CurveByName resolves the Group backend for a Curve field value. An empty
+// name means "secp256k1", so sessions/rings serialized before this field
+// existed keep working.
+func CurveByName(name string) (Group, error) {
+	switch name {
+	case "", "secp256k1":
+		return Secp256k1Group{}, nil
+	case "ed25519":
+		return Ed25519Group{}, nil
+	default:
+		return nil, fmt.Errorf("Unknown curve: %v", name)
+	}
+}
+
+// Secp256k1Group implements Group over the module's existing CurvePoint
+// type, so it's just a thin adapter around functions that already exist.
+type Secp256k1Group struct{}
+
+func (Secp256k1Group) Name() string           { return "secp256k1" }
+func (Secp256k1Group) RandomScalar() *big.Int { return CurvePoint{}.RandomN() }
+func (Secp256k1Group) Order() *big.Int        { return CurvePoint{}.Order() }
+
+func (Secp256k1Group) ScalarBaseMult(k *big.Int) GroupElement {
+	return CurvePoint{}.ScalarBaseMult(k)
+}
+
+func (Secp256k1Group) ScalarMult(p GroupElement, k *big.Int) GroupElement {
+	return p.(CurvePoint).ScalarMult(k)
+}
+
+func (Secp256k1Group) Add(a, b GroupElement) GroupElement {
+	return a.(CurvePoint).Add(b.(CurvePoint))
+}
+
+func (Secp256k1Group) HashToPoint(h [32]byte) GroupElement {
+	return NewCurvePointFromHash(h)
+}
+
+func (Secp256k1Group) Marshal(p GroupElement) []byte { return p.Marshal() }
+
+func (Secp256k1Group) Unmarshal(data []byte) (GroupElement, error) {
+	p, err := NewCurvePointFromBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	return *p, nil
+}
+
+// edOrder is l, the order of the Ed25519 prime-order subgroup.
+var edOrder, _ = new(big.Int).SetString("1000000000000000000000000000000014def9dea2f79cd65812631a5cf5d3ed", 16)
+
+// scalarToLE encodes x mod edOrder as the little-endian, fixed 32-byte
+// canonical scalar encoding edwards25519 expects.
+func scalarToLE(x *big.Int) []byte {
+	be := new(big.Int).Mod(x, edOrder).Bytes()
+	le := make([]byte, 32)
+	for i, b := range be {
+		le[len(be)-1-i] = b
+	}
+	return le
+}
+
+// leToScalar is the inverse of scalarToLE.
+func leToScalar(le []byte) *big.Int {
+	be := make([]byte, len(le))
+	for i, b := range le {
+		be[len(le)-1-i] = b
+	}
+	return new(big.Int).SetBytes(be)
+}
+
+// Ed25519Point is a point on the Edwards25519 curve.
+type Ed25519Point struct {
+	p *edwards25519.Point
+}
+
+// Marshal returns the standard 32-byte compressed encoding of the point.
+func (e Ed25519Point) Marshal() []byte { return e.p.Bytes() }
+
+// Ed25519Group implements Group over Edwards25519, backing stealth
+// derivation and ring signing, plus X25519 for ECDH in deriveSharedSecret.
+type Ed25519Group struct{}
+
+func (Ed25519Group) Name() string    { return "ed25519" }
+func (Ed25519Group) Order() *big.Int { return edOrder }
+
+// RandomScalar generates a scalar the standard Ed25519 way: 32 random
+// bytes, RFC 8032 clamped. Every other Group clamps nothing and just
+// reduces mod its order, but here clamping matters beyond key generation:
+// x25519Scalar relies on every Ed25519 private key already being in
+// clamped form so the X25519 ECDH in deriveSharedSecret stays consistent
+// with the Edwards key the rest of this Group derives from it.
+func (Ed25519Group) RandomScalar() *big.Int {
+	var buf [32]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		panic(err)
+	}
+	return leToScalar(clampScalarBytes(buf[:]))
+}
+
+func (Ed25519Group) ScalarBaseMult(k *big.Int) GroupElement {
+	s, err := edwards25519.NewScalar().SetCanonicalBytes(scalarToLE(k))
+	if err != nil {
+		panic(err)
+	}
+	return Ed25519Point{new(edwards25519.Point).ScalarBaseMult(s)}
+}
+
+func (Ed25519Group) ScalarMult(p GroupElement, k *big.Int) GroupElement {
+	s, err := edwards25519.NewScalar().SetCanonicalBytes(scalarToLE(k))
+	if err != nil {
+		panic(err)
+	}
+	return Ed25519Point{new(edwards25519.Point).ScalarMult(s, p.(Ed25519Point).p)}
+}
+
+func (Ed25519Group) Add(a, b GroupElement) GroupElement {
+	return Ed25519Point{new(edwards25519.Point).Add(a.(Ed25519Point).p, b.(Ed25519Point).p)}
+}
+
+// HashToPoint maps an arbitrary 32-byte hash onto the curve, the same role
+// NewCurvePointFromHash plays for secp256k1: the hash is reduced to a
+// scalar (SetUniformBytes lives on *Scalar, not *Point) and then
+// multiplied by the base point.
+func (Ed25519Group) HashToPoint(h [32]byte) GroupElement {
+	wide := sha512.Sum512(h[:])
+	s, err := edwards25519.NewScalar().SetUniformBytes(wide[:])
+	if err != nil {
+		panic(err)
+	}
+	return Ed25519Point{new(edwards25519.Point).ScalarBaseMult(s)}
+}
+
+func (Ed25519Group) Marshal(p GroupElement) []byte { return p.Marshal() }
+
+func (Ed25519Group) Unmarshal(data []byte) (GroupElement, error) {
+	p, err := new(edwards25519.Point).SetBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	return Ed25519Point{p}, nil
+}
+
+// edwardsToMontgomeryU converts a compressed Edwards25519 y-coordinate to
+// the Montgomery u-coordinate used by X25519, via the standard birational
+// map u = (1+y)/(1-y) mod p.
+func edwardsToMontgomeryU(edPub []byte) []byte {
+	var p, _ = new(big.Int).SetString("7fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffed", 16)
+
+	le := make([]byte, 32)
+	copy(le, edPub)
+	le[31] &= 0x7f // strip the sign bit, it's not part of y
+
+	be := make([]byte, 32)
+	for i, b := range le {
+		be[31-i] = b
+	}
+	y := new(big.Int).SetBytes(be)
+
+	num := new(big.Int).Add(bigOne, y)
+	den := new(big.Int).Sub(bigOne, y)
+	den.ModInverse(den, p)
+
+	u := new(big.Int).Mul(num, den)
+	u.Mod(u, p)
+
+	ube := u.Bytes()
+	ule := make([]byte, 32)
+	for i, b := range ube {
+		ule[len(ube)-1-i] = b
+	}
+	return ule
+}
+
+// clampScalarBytes applies the RFC 7748/8032 "clamping" bit-twiddle to a
+// 32-byte little-endian scalar in place and returns it.
+func clampScalarBytes(le []byte) []byte {
+	le[0] &= 248
+	le[31] &= 127
+	le[31] |= 64
+	return le
+}
+
+// x25519Scalar returns priv's raw (un-reduced) little-endian 32-byte
+// encoding, clamped. This deliberately does NOT go through scalarToLE:
+// scalarToLE reduces modulo edOrder for the Edwards scalar arithmetic, but
+// clamping sets a bit above edOrder, so reducing first would hand X25519 a
+// different integer than the one that was clamped - breaking the
+// correspondence x25519Scalar relies on between a key's Edwards point
+// (ScalarBaseMult of the clamped value, reduced mod edOrder internally)
+// and its Montgomery image (the X25519 ladder run on that same clamped
+// value, unreduced).
+func x25519Scalar(priv *big.Int) []byte {
+	be := priv.Bytes()
+	le := make([]byte, 32)
+	for i, b := range be {
+		le[len(be)-1-i] = b
+	}
+	return clampScalarBytes(le)
+}
+
+// x25519SharedSecret derives an ECDH shared secret over Curve25519 from an
+// Ed25519-style scalar/point pair, for Group == Ed25519Group. It rejects
+// key reflection (theirPub == myPub), the same guard typical curve25519
+// ECDH wrappers apply so a peer can't trick you into "sharing a secret"
+// with yourself.
+//
+// Both sides must derive their scalar via x25519Scalar, not scalarToLE:
+// RandomScalar already returns its value in clamped form, and clamping
+// only round-trips correctly through the Edwards <-> Montgomery
+// birational map when both ends use literally the same clamped integer -
+// reducing it mod edOrder first (as scalarToLE does) yields a different
+// integer and two honest parties would derive different shared secrets.
+func x25519SharedSecret(myPriv *big.Int, theirPub Ed25519Point) ([]byte, error) {
+	myPub := Ed25519Group{}.ScalarBaseMult(myPriv).(Ed25519Point)
+	if string(myPub.Marshal()) == string(theirPub.Marshal()) {
+		return nil, fmt.Errorf("Refusing to derive a shared secret with own public key")
+	}
+
+	scalar := x25519Scalar(myPriv)
+	point := edwardsToMontgomeryU(theirPub.Marshal())
+
+	secret, err := curve25519.X25519(scalar, point)
+	if err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
@@ -0,0 +1,162 @@
+// Copyright (c) 2020 Prodefi -  D FROZEN SOFT PRIVATE LIMITED
+
+// Package hd implements BIP32-style hierarchical deterministic derivation
+// for Aploune master keys, so a wallet can recover every stealth address
+// it has ever issued from nothing but its original seed.
+package hd
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+)
+
+// curveOrder is the order of the secp256k1 base point - the same curve
+// CurvePoint{}.Order() reports in the root package, since master keys
+// derived here are meant to seed a StealthSession over that curve.
+var curveOrder, _ = new(big.Int).SetString("fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141", 16)
+
+const hardenedOffset = uint32(1) << 31
+
+// ExtendedKey is one node of a BIP32-style derivation tree: a secret
+// scalar plus the chain code needed to derive its children.
+type ExtendedKey struct {
+	Key       *big.Int
+	ChainCode []byte
+	Depth     uint8
+	Index     uint32
+}
+
+// NewMasterFromSeed derives the root ExtendedKey from a wallet seed via
+//
+//	I = HMAC-SHA512(key="Aploune seed", seed)
+//
+// The left 32 bytes of I become the master scalar, the right 32 the
+// master chain code.
+func NewMasterFromSeed(seed []byte) (*ExtendedKey, error) {
+	mac := hmac.New(sha512.New, []byte("Aploune seed"))
+	mac.Write(seed)
+	I := mac.Sum(nil)
+
+	key := new(big.Int).SetBytes(I[:32])
+	key.Mod(key, curveOrder)
+	if key.Sign() == 0 {
+		return nil, errors.New("hd: seed produced an invalid (zero) master key")
+	}
+
+	return &ExtendedKey{Key: key, ChainCode: I[32:]}, nil
+}
+
+// IsHardened reports whether index designates a hardened child, i.e.
+// index >= 2^31 ("i'" in BIP32 path notation).
+func IsHardened(index uint32) bool {
+	return index >= hardenedOffset
+}
+
+// Derive returns the index'th child of k.
+//
+// Non-hardened children mix the parent's serialized public point into the
+// HMAC input, so they can be derived from the public key alone:
+//
+//	I = HMAC-SHA512(chainCode, serP(K) || ser32(i))
+//
+// Hardened children (index >= 2^31) mix in the parent's private scalar
+// instead, so a hardened child can never be derived without it:
+//
+//	I = HMAC-SHA512(chainCode, 0x00 || ser256(k) || ser32(i))
+//
+// Either way, the left 32 bytes of I are added mod curveOrder to the
+// parent scalar to get the child scalar, and the right 32 bytes become
+// the child's chain code.
+func (k *ExtendedKey) Derive(index uint32) (*ExtendedKey, error) {
+	var data []byte
+	if IsHardened(index) {
+		data = append([]byte{0x00}, ser256(k.Key)...)
+	} else {
+		data = serP(k.Key)
+	}
+	data = append(data, ser32(index)...)
+
+	mac := hmac.New(sha512.New, k.ChainCode)
+	mac.Write(data)
+	I := mac.Sum(nil)
+
+	il := new(big.Int).SetBytes(I[:32])
+	if il.Cmp(curveOrder) >= 0 {
+		return nil, fmt.Errorf("hd: invalid child index %d, IL >= curve order", index)
+	}
+
+	child := new(big.Int).Add(k.Key, il)
+	child.Mod(child, curveOrder)
+	if child.Sign() == 0 {
+		return nil, fmt.Errorf("hd: invalid child index %d, derived a zero key", index)
+	}
+
+	return &ExtendedKey{
+		Key:       child,
+		ChainCode: I[32:],
+		Depth:     k.Depth + 1,
+		Index:     index,
+	}, nil
+}
+
+// DerivePath walks k down a BIP32-style path such as "m/44'/0'/0'/0/n",
+// where a trailing ' or h marks a hardened index.
+func (k *ExtendedKey) DerivePath(path string) (*ExtendedKey, error) {
+	segments := strings.Split(path, "/")
+	if len(segments) == 0 || segments[0] != "m" {
+		return nil, fmt.Errorf("hd: path must start with \"m\": %q", path)
+	}
+
+	current := k
+	for _, seg := range segments[1:] {
+		hardened := strings.HasSuffix(seg, "'") || strings.HasSuffix(seg, "h")
+		seg = strings.TrimSuffix(strings.TrimSuffix(seg, "'"), "h")
+
+		n, err := strconv.ParseUint(seg, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("hd: invalid path segment %q: %w", seg, err)
+		}
+
+		index := uint32(n)
+		if hardened {
+			index += hardenedOffset
+		}
+
+		var err2 error
+		current, err2 = current.Derive(index)
+		if err2 != nil {
+			return nil, err2
+		}
+	}
+
+	return current, nil
+}
+
+// ser256 big-endian encodes a scalar as a fixed 32-byte string.
+func ser256(x *big.Int) []byte {
+	var b [32]byte
+	x.FillBytes(b[:])
+	return b[:]
+}
+
+// ser32 big-endian encodes a child index as a fixed 4-byte string.
+func ser32(i uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, i)
+	return b
+}
+
+// serP derives the public point for scalar and returns its standard
+// 33-byte compressed (SEC1) encoding.
+func serP(scalar *big.Int) []byte {
+	_, pub := btcec.PrivKeyFromBytes(ser256(scalar))
+	return pub.SerializeCompressed()
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2020 Prodefi -  D FROZEN SOFT PRIVATE LIMITED
+
+package hd
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func mustSeed(t *testing.T, hexSeed string) []byte {
+	t.Helper()
+	seed, err := hex.DecodeString(hexSeed)
+	if err != nil {
+		t.Fatalf("invalid test seed: %v", err)
+	}
+	return seed
+}
+
+func TestNewMasterFromSeedIsDeterministic(t *testing.T) {
+	seed := mustSeed(t, "000102030405060708090a0b0c0d0e0f")
+
+	m1, err := NewMasterFromSeed(seed)
+	if err != nil {
+		t.Fatalf("NewMasterFromSeed: %v", err)
+	}
+	m2, err := NewMasterFromSeed(seed)
+	if err != nil {
+		t.Fatalf("NewMasterFromSeed: %v", err)
+	}
+
+	if m1.Key.Cmp(m2.Key) != 0 {
+		t.Fatalf("master key not deterministic: %v != %v", m1.Key, m2.Key)
+	}
+	if string(m1.ChainCode) != string(m2.ChainCode) {
+		t.Fatalf("chain code not deterministic")
+	}
+}
+
+func TestDerivePathMatchesManualDerive(t *testing.T) {
+	seed := mustSeed(t, "000102030405060708090a0b0c0d0e0f")
+	master, err := NewMasterFromSeed(seed)
+	if err != nil {
+		t.Fatalf("NewMasterFromSeed: %v", err)
+	}
+
+	got, err := master.DerivePath("m/44'/0'/0'/0/5")
+	if err != nil {
+		t.Fatalf("DerivePath: %v", err)
+	}
+
+	want := master
+	for _, index := range []uint32{44 + hardenedOffset, 0 + hardenedOffset, 0 + hardenedOffset, 0, 5} {
+		want, err = want.Derive(index)
+		if err != nil {
+			t.Fatalf("Derive: %v", err)
+		}
+	}
+
+	if got.Key.Cmp(want.Key) != 0 {
+		t.Fatalf("DerivePath produced a different key than manual Derive calls")
+	}
+	if got.Depth != want.Depth {
+		t.Fatalf("DerivePath depth = %d, want %d", got.Depth, want.Depth)
+	}
+}
+
+func TestIsHardened(t *testing.T) {
+	if IsHardened(0) {
+		t.Fatalf("index 0 should not be hardened")
+	}
+	if !IsHardened(hardenedOffset) {
+		t.Fatalf("index 2^31 should be hardened")
+	}
+	if !IsHardened(hardenedOffset + 44) {
+		t.Fatalf("index 2^31+44 should be hardened")
+	}
+}
+
+func TestDerivePathRejectsMissingRoot(t *testing.T) {
+	seed := mustSeed(t, "000102030405060708090a0b0c0d0e0f")
+	master, err := NewMasterFromSeed(seed)
+	if err != nil {
+		t.Fatalf("NewMasterFromSeed: %v", err)
+	}
+
+	if _, err := master.DerivePath("44'/0'"); err == nil {
+		t.Fatalf("expected an error for a path missing its \"m\" root")
+	}
+}
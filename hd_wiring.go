@@ -0,0 +1,19 @@
+// Copyright (c) 2020 Prodefi -  D FROZEN SOFT PRIVATE LIMITED
+
+package main
+
+import "github.com/Prodefi/aploune/hd"
+
+// NewStealthSessionFromPath derives a StealthSession whose mySecret is the
+// scalar at path in an HD tree rooted at master, rather than a one-off
+// random key. Given the same seed and path, a wallet can regenerate the
+// exact series of stealth addresses it issued before, instead of having
+// to keep every ephemeral secret it ever used.
+func NewStealthSessionFromPath(master *hd.ExtendedKey, path string, theirPublic *CurvePoint, nonceOffset int, addressCount int) (*StealthSession, error) {
+	child, err := master.DerivePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewStealthSession(child.Key, theirPublic, nonceOffset, addressCount)
+}
@@ -0,0 +1,137 @@
+// Copyright (c) 2020 Prodefi -  D FROZEN SOFT PRIVATE LIMITED
+
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+)
+
+// PaymentCode is the public, shareable half of a dual-key stealth address:
+// a view public key, used only to recognize incoming payments, and a
+// spend public key, which only the matching SpendSecret can move.
+type PaymentCode struct {
+	ViewPub  CurvePoint `json:"viewPub"`
+	SpendPub CurvePoint `json:"spendPub"`
+}
+
+// ViewOnlyStealthSession is a watch-only wallet: it holds a ViewSecret and
+// a SpendPub, enough to recognize dual-key stealth payments addressed to
+// it, but not enough to spend them.
+type ViewOnlyStealthSession struct {
+	ViewSecret *big.Int   `json:"viewSecret"`
+	SpendPub   CurvePoint `json:"spendPub"`
+}
+
+// FullStealthSession holds both halves of a dual-key stealth identity: the
+// ViewSecret needed to scan for payments, and the SpendSecret needed to
+// actually move them once found.
+type FullStealthSession struct {
+	ViewSecret  *big.Int `json:"viewSecret"`
+	SpendSecret *big.Int `json:"spendSecret"`
+}
+
+// NewFullStealthSession derives a dual-key stealth identity from two
+// independent secrets, so the ability to scan for payments can be handed
+// to a less-trusted process without also handing over the ability to
+// spend them.
+func NewFullStealthSession(viewSecret, spendSecret *big.Int) (*FullStealthSession, error) {
+	if false == isValidSecretKey(viewSecret) {
+		return nil, fmt.Errorf("Invalid view secret key: %v", viewSecret)
+	}
+	if false == isValidSecretKey(spendSecret) {
+		return nil, fmt.Errorf("Invalid spend secret key: %v", spendSecret)
+	}
+
+	return &FullStealthSession{viewSecret, spendSecret}, nil
+}
+
+// PaymentCode returns the public half of fs: what a sender needs in order
+// to pay it.
+func (fs *FullStealthSession) PaymentCode() *PaymentCode {
+	return &PaymentCode{derivePublicKey(fs.ViewSecret), derivePublicKey(fs.SpendSecret)}
+}
+
+// ViewOnly returns the watch-only half of fs, suitable for handing to a
+// scanning process that should never be able to spend what it finds.
+func (fs *FullStealthSession) ViewOnly() *ViewOnlyStealthSession {
+	return &ViewOnlyStealthSession{fs.ViewSecret, derivePublicKey(fs.SpendSecret)}
+}
+
+// paymentCodeOffset computes H(sharedSecret || i) mod N, the scalar a
+// dual-key stealth payment adds on top of the spend key: the sender adds
+// it to SpendPub as a point, the spender adds it to SpendSecret as a
+// scalar, and they agree on it because both sides can compute the same
+// ECDH shared secret between the ephemeral keypair and the view keypair.
+func paymentCodeOffset(sharedSecret []byte, i *big.Int) *big.Int {
+	secret := append(sharedSecret, i.Bytes()...)
+	hashout := sha256.Sum256(secret)
+
+	H := new(big.Int).SetBytes(hashout[:])
+	return H.Mod(H, CurvePoint{}.Order())
+}
+
+// DeriveSpendPublic is the sender's half of a dual-key stealth payment. It
+// generates a fresh ephemeral keypair (r, R = r·G) and the one-time output
+// key the owner of pc can claim:
+//
+//   spk = SpendPub + H(ecdh(r, ViewPub) || i)·G
+//
+// R must be published alongside the payment (as the transaction's public
+// key) so the recipient's watch-only wallet can find spk with
+// ViewOnlyStealthSession.Scan.
+func DeriveSpendPublic(pc *PaymentCode, i *big.Int) (spk *CurvePoint, R *CurvePoint, err error) {
+	if nil == pc {
+		return nil, nil, fmt.Errorf("Null payment code provided")
+	}
+
+	r := CurvePoint{}.RandomN()
+	shared := deriveSharedSecret(r, &pc.ViewPub)
+	offset := paymentCodeOffset(shared, i)
+
+	spkVal := pc.SpendPub.Add(derivePublicKey(offset))
+	rPub := derivePublicKey(r)
+	return &spkVal, &rPub, nil
+}
+
+// Scan derives, for each ephemeral public key in txPubKeys, every one-time
+// output key a sender could have produced for some index i in
+// [0, lookahead), so a light client holding only vo can check those
+// against what it observes on-chain - without ever touching a
+// SpendSecret. DeriveSpendPublic lets a sender pick any i (e.g. to issue
+// several outputs against the same R), so vo can't recover i from a
+// ephemeral key's position in txPubKeys; instead it searches a bounded
+// range per R, the same "gap limit" convention HD wallets use when
+// scanning address indices.
+func (vo *ViewOnlyStealthSession) Scan(txPubKeys []CurvePoint, lookahead int) []StealthAddress {
+	var addresses []StealthAddress
+
+	for _, R := range txPubKeys {
+		shared := deriveSharedSecret(vo.ViewSecret, &R)
+
+		for idx := 0; idx < lookahead; idx++ {
+			i := new(big.Int).SetInt64(int64(idx))
+			offset := paymentCodeOffset(shared, i)
+
+			spk := vo.SpendPub.Add(derivePublicKey(offset))
+			addresses = append(addresses, StealthAddress{spk, i})
+		}
+	}
+
+	return addresses
+}
+
+// SpendSecretFor reconstructs the one-time spending scalar for the output
+// found at ephemeral public key R, index i - everything Scan needed, plus
+// the SpendSecret that only the online spender holds:
+//
+//   ssk = SpendSecret + H(ecdh(ViewSecret, R) || i)
+//
+func (fs *FullStealthSession) SpendSecretFor(R *CurvePoint, i *big.Int) *big.Int {
+	shared := deriveSharedSecret(fs.ViewSecret, R)
+	offset := paymentCodeOffset(shared, i)
+
+	ssk := new(big.Int).Add(fs.SpendSecret, offset)
+	return ssk.Mod(ssk, CurvePoint{}.Order())
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2020 Prodefi -  D FROZEN SOFT PRIVATE LIMITED
+
+package main
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestDeriveSpendPublicFoundByScan(t *testing.T) {
+	fs, err := NewFullStealthSession(CurvePoint{}.RandomN(), CurvePoint{}.RandomN())
+	if err != nil {
+		t.Fatalf("NewFullStealthSession: %v", err)
+	}
+	pc := fs.PaymentCode()
+	vo := fs.ViewOnly()
+
+	i := big.NewInt(3)
+	spk, R, err := DeriveSpendPublic(pc, i)
+	if err != nil {
+		t.Fatalf("DeriveSpendPublic: %v", err)
+	}
+
+	found := vo.Scan([]CurvePoint{*R}, 5)
+
+	var match *StealthAddress
+	for idx := range found {
+		if string(found[idx].Public.(CurvePoint).Marshal()) == string(spk.Marshal()) {
+			match = &found[idx]
+			break
+		}
+	}
+	if match == nil {
+		t.Fatalf("Scan did not recover the one-time output key at index %v", i)
+	}
+	if match.Nonce.Cmp(i) != 0 {
+		t.Fatalf("Scan recovered index %v, want %v", match.Nonce, i)
+	}
+}
+
+func TestDeriveSpendPublicOutsideLookaheadNotFound(t *testing.T) {
+	fs, err := NewFullStealthSession(CurvePoint{}.RandomN(), CurvePoint{}.RandomN())
+	if err != nil {
+		t.Fatalf("NewFullStealthSession: %v", err)
+	}
+	pc := fs.PaymentCode()
+	vo := fs.ViewOnly()
+
+	spk, R, err := DeriveSpendPublic(pc, big.NewInt(10))
+	if err != nil {
+		t.Fatalf("DeriveSpendPublic: %v", err)
+	}
+
+	found := vo.Scan([]CurvePoint{*R}, 5)
+	for _, a := range found {
+		if string(a.Public.(CurvePoint).Marshal()) == string(spk.Marshal()) {
+			t.Fatalf("Scan found an output beyond its lookahead window")
+		}
+	}
+}
+
+func TestSpendSecretForMatchesDerivedSpendPublic(t *testing.T) {
+	fs, err := NewFullStealthSession(CurvePoint{}.RandomN(), CurvePoint{}.RandomN())
+	if err != nil {
+		t.Fatalf("NewFullStealthSession: %v", err)
+	}
+	pc := fs.PaymentCode()
+
+	i := big.NewInt(1)
+	spk, R, err := DeriveSpendPublic(pc, i)
+	if err != nil {
+		t.Fatalf("DeriveSpendPublic: %v", err)
+	}
+
+	ssk := fs.SpendSecretFor(R, i)
+	got := derivePublicKey(ssk)
+	if string(got.Marshal()) != string(spk.Marshal()) {
+		t.Fatalf("derivePublicKey(SpendSecretFor(...)) != DeriveSpendPublic's spk")
+	}
+}
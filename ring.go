@@ -3,29 +3,52 @@
 package main
 
 import (
+	"bytes"
 	"crypto/sha256"
 	"encoding/json"
+	"fmt"
 	"math/big"
 )
 
 // A Ring is a number of public/private key pairs
+//
+// Curve names the Group backend ("secp256k1" or "ed25519") that PubKeys
+// belong to. PubKeyIndex and Generate work over whichever Group Curve
+// names, via SignatureG/VerifySignatureG. Signature/Signatures/
+// VerifySignature are secp256k1-only: they build a RingSignature, and
+// RingSignature.Tau is declared as CurvePoint rather than GroupElement, so
+// that path can't follow PubKeys onto a second curve without changing
+// RingSignature itself.
 type Ring struct {
-	PubKeys  []CurvePoint `json:"pubkeys"`
-	PrivKeys []*big.Int   `json:"privkeys"`
+	Curve    string         `json:"curve"`
+	PubKeys  []GroupElement `json:"pubkeys"`
+	PrivKeys []*big.Int     `json:"privkeys"`
 }
 
 // MarshalJSON converts a Ring to a JSON representation
 func (r *Ring) MarshalJSON() ([]byte, error) {
+	group, err := CurveByName(r.Curve)
+	if err != nil {
+		return nil, err
+	}
+
 	pks := make([]*hexBig, len(r.PrivKeys))
 	for i, v := range r.PrivKeys {
 		pks[i] = (*hexBig)(v)
 	}
 
+	pubs := make([]hexBytes, len(r.PubKeys))
+	for i, v := range r.PubKeys {
+		pubs[i] = hexBytes(group.Marshal(v))
+	}
+
 	return json.Marshal(&struct {
-		PubKeys  []CurvePoint `json:"pubkeys"`
-		PrivKeys []*hexBig    `json:"privkeys"`
+		Curve    string     `json:"curve"`
+		PubKeys  []hexBytes `json:"pubkeys"`
+		PrivKeys []*hexBig  `json:"privkeys"`
 	}{
-		PubKeys:  r.PubKeys,
+		Curve:    r.Curve,
+		PubKeys:  pubs,
 		PrivKeys: pks,
 	})
 }
@@ -33,20 +56,35 @@ func (r *Ring) MarshalJSON() ([]byte, error) {
 // UnmarshalJSON converts a JSON representation to a Ring struct
 func (r *Ring) UnmarshalJSON(data []byte) error {
 	var aux struct {
-		PubKeys  []CurvePoint `json:"pubkeys"`
-		PrivKeys []*hexBig    `json:"privkeys"`
+		Curve    string     `json:"curve"`
+		PubKeys  []hexBytes `json:"pubkeys"`
+		PrivKeys []*hexBig  `json:"privkeys"`
 	}
 	err := json.Unmarshal(data, &aux)
 	if err != nil {
 		return err
 	}
 
+	group, err := CurveByName(aux.Curve)
+	if err != nil {
+		return err
+	}
+
+	pubs := make([]GroupElement, len(aux.PubKeys))
+	for i, v := range aux.PubKeys {
+		pubs[i], err = group.Unmarshal(v)
+		if err != nil {
+			return err
+		}
+	}
+
 	pks := make([]*big.Int, len(aux.PrivKeys))
 	for i, v := range aux.PrivKeys {
 		pks[i] = (*big.Int)(v)
 	}
+	r.Curve = aux.Curve
 	r.PrivKeys = pks[:]
-	r.PubKeys = aux.PubKeys
+	r.PubKeys = pubs
 	return nil
 }
 
@@ -69,25 +107,39 @@ func (r Ring) PublicKeysHashed() [sha256.Size]byte {
 	return out
 }
 
-// Generate creates public and private keypairs for a ring with the size of n
+// Generate creates public and private keypairs for a ring with the size of
+// n, over whichever Group r.Curve names (defaulting to secp256k1). The
+// keys it produces are signable with SignatureG/VerifySignatureG on either
+// backend; the secp256k1-only Signature/VerifySignature additionally work
+// when r.Curve is secp256k1.
 func (r *Ring) Generate(n int) error {
+	if r.Curve == "" {
+		r.Curve = Secp256k1Group{}.Name()
+	}
+	group, err := CurveByName(r.Curve)
+	if err != nil {
+		return err
+	}
+
 	for i := 0; i < n; i++ {
-		public, private, err := generateKeyPair()
-		if err != nil {
-			return err
-		}
+		private := group.RandomScalar()
+		public := group.ScalarBaseMult(private)
 		r.PrivKeys = append(r.PrivKeys, private)
-		r.PubKeys = append(r.PubKeys, *public)
+		r.PubKeys = append(r.PubKeys, public)
 	}
 
 	return nil
 }
 
-// PubKeyIndex returns the index of a public key
-func (r *Ring) PubKeyIndex(pk CurvePoint) int {
+// PubKeyIndex returns the index of a public key, or -1 if it isn't in the
+// ring. It compares by marshaled bytes rather than ==, since GroupElement
+// implementations such as Ed25519Point wrap a pointer and aren't safely
+// comparable with ==.
+func (r *Ring) PubKeyIndex(pk GroupElement) int {
 
+	want := pk.Marshal()
 	for i, pub := range r.PubKeys {
-		if pub == pk {
+		if bytes.Equal(pub.Marshal(), want) {
 			return i
 		}
 	}
@@ -96,8 +148,16 @@ func (r *Ring) PubKeyIndex(pk CurvePoint) int {
 
 }
 
-// Signature generates a signature
+// Signature generates a signature. It only supports secp256k1: RingSignature
+// is defined outside this package's own files with Tau typed as CurvePoint,
+// so this construction can't follow PubKeys onto a second Group without a
+// change to RingSignature itself. Rings built over another Curve should use
+// SignatureG instead.
 func (r *Ring) Signature(pk *big.Int, message []byte, signer int) (*RingSignature, error) {
+	if secp := (Secp256k1Group{}).Name(); r.Curve != "" && r.Curve != secp {
+		return nil, fmt.Errorf("Signature only supports secp256k1, ring uses curve: %v", r.Curve)
+	}
+
 	N := CurvePoint{}.Order()
 
 	// Message is a 256 bit token which uniquely identifies the Ring and the public keys
@@ -126,7 +186,7 @@ func (r *Ring) Signature(pk *big.Int, message []byte, signer int) (*RingSignatur
 			cj := CurvePoint{}.RandomN()
 			tj := CurvePoint{}.RandomN()
 
-			a = r.PubKeys[j].ParameterPointAdd(tj, cj)
+			a = r.PubKeys[j].(CurvePoint).ParameterPointAdd(tj, cj)
 
 			b = hashp.HashPointAdd(hashSP, tj, cj)
 			ctlist = append(ctlist, cj)
@@ -183,9 +243,19 @@ func (r *Ring) Signatures(message []byte) ([]RingSignature, error) {
 
 // VerifySignature verifys a signature given a message
 func (r *Ring) VerifySignature(message []byte, sigma RingSignature) bool {
-	// ring verification
-	// assumes R = pk1, pk2, ..., pkn
-	// sigma = H(m||R)^x_i, c1, t1, ..., cn, tn = taux, tauy, c1, t1, ..., cn, tn
+	csum, hashout := r.verificationScalars(message, sigma)
+	return csum.Cmp(hashout) == 0
+}
+
+// verificationScalars recomputes the two scalars that a valid signature
+// must agree on: csum, the sum of the challenge values baked into sigma,
+// and hashout, the Fiat-Shamir hash recomputed from the ring and the
+// message. VerifySignature checks them for equality directly; BatchVerify
+// folds them across many signatures instead.
+//
+// ring verification assumes R = pk1, pk2, ..., pkn
+// sigma = H(m||R)^x_i, c1, t1, ..., cn, tn = taux, tauy, c1, t1, ..., cn, tn
+func (r *Ring) verificationScalars(message []byte, sigma RingSignature) (csum, hashout *big.Int) {
 	tau := sigma.Tau
 	ctlist := sigma.Ctlist
 	n := len(r.PubKeys)
@@ -197,7 +267,7 @@ func (r *Ring) VerifySignature(message []byte, sigma RingSignature) bool {
 
 	hashAcc := sha256.Sum256(append(hashp.Marshal()[:32], tau.Marshal()...))
 
-	csum := big.NewInt(0)
+	csum = big.NewInt(0)
 
 	for j := 0; j < n; j++ {
 		cj := ctlist[2*j]
@@ -205,8 +275,8 @@ func (r *Ring) VerifySignature(message []byte, sigma RingSignature) bool {
 		cj.Mod(cj, N)
 		tj.Mod(tj, N)
 
-		yc := r.PubKeys[j].ScalarMult(cj)     // y^c = g^(xc)
-		gt := CurvePoint{}.ScalarBaseMult(tj) // g^t + y^c
+		yc := r.PubKeys[j].(CurvePoint).ScalarMult(cj) // y^c = g^(xc)
+		gt := CurvePoint{}.ScalarBaseMult(tj)          // g^t + y^c
 		gt = gt.Add(yc)
 
 		tauc := tau.ScalarMult(cj) //H(m||R)^(xc)
@@ -219,8 +289,201 @@ func (r *Ring) VerifySignature(message []byte, sigma RingSignature) bool {
 		csum.Mod(csum, N)
 	}
 
-	hashout := new(big.Int).SetBytes(hashAcc[:])
+	hashout = new(big.Int).SetBytes(hashAcc[:])
 	hashout.Mod(hashout, N)
 	csum.Mod(csum, N)
-	return csum.Cmp(hashout) == 0
+	return csum, hashout
+}
+
+// LinkTag returns the canonical byte representation of the signature's key
+// image (Tau). Tau is H(m||R)^x, tied to the message as well as the
+// signer, so two signatures share a LinkTag only if the same signer
+// produced them both over the same message and ring - this is what lets a
+// verifier catch a signer replaying their exact signature, not cross-
+// message double-spending.
+func (sig RingSignature) LinkTag() []byte {
+	return sig.Tau.Marshal()
+}
+
+// LinkedSignatures partitions sigs into groups that share the same key
+// image, i.e. were produced by the same signer replaying the same message.
+// Each inner slice holds the indices (into sigs) of one such group, in the
+// order they were first seen. A signature with no repeat still gets its
+// own single-element group; callers looking for replay should filter for
+// groups with len > 1.
+func (r *Ring) LinkedSignatures(sigs []RingSignature) [][]int {
+	order := make([]string, 0, len(sigs))
+	groups := make(map[string][]int)
+
+	for i, sig := range sigs {
+		tag := string(sig.LinkTag())
+		if _, seen := groups[tag]; !seen {
+			order = append(order, tag)
+		}
+		groups[tag] = append(groups[tag], i)
+	}
+
+	out := make([][]int, len(order))
+	for i, tag := range order {
+		out[i] = groups[tag]
+	}
+
+	return out
+}
+
+// BatchVerify checks many (message, signature) pairs against the ring in
+// one pass. Each pair still recomputes its own Fiat-Shamir hash chain via
+// verificationScalars - that chain is what ties csum/hashout to the actual
+// ring members, so its O(k·n) point multiplications aren't avoidable
+// without changing the signature format itself. What BatchVerify batches
+// is the final comparison: instead of testing each csum_i against its own
+// hashout_i in turn, it folds all k checks into one using random scalars
+// r_i ∈ [1, N): Σ r_i·(csum_i - hashout_i) ≡ 0 (mod N). That sum is zero
+// with overwhelming probability iff every individual equation holds, so a
+// forged signature hiding among genuine ones is caught with probability
+// 1 - 1/N. The payoff is a single accept/reject verdict over the whole
+// batch in one pass, not fewer point operations than k sequential
+// VerifySignature calls.
+func (r *Ring) BatchVerify(messages [][]byte, sigs []RingSignature) bool {
+	if len(messages) != len(sigs) || len(messages) == 0 {
+		return false
+	}
+
+	N := CurvePoint{}.Order()
+	acc := big.NewInt(0)
+
+	for i, sig := range sigs {
+		ri := CurvePoint{}.RandomN()
+
+		csum, hashout := r.verificationScalars(messages[i], sig)
+		diff := new(big.Int).Sub(csum, hashout)
+		diff.Mod(diff, N)
+
+		term := new(big.Int).Mul(ri, diff)
+		acc.Add(acc, term)
+		acc.Mod(acc, N)
+	}
+
+	return acc.Sign() == 0
+}
+
+// GenericRingSignature is RingSignature's Group-backed counterpart: the
+// same LSAG construction (Tau as key image, Ctlist as the interleaved
+// c_j/t_j challenge/response pairs), but with Tau held as a GroupElement
+// so it can be produced and checked over any registered Group, not just
+// secp256k1.
+type GenericRingSignature struct {
+	Tau    GroupElement
+	Ctlist []*big.Int
+}
+
+// SignatureG is Signature's Group-backed counterpart: it signs over
+// r.Curve's Group instead of assuming secp256k1, so it also works for
+// rings built with Generate over e.g. "ed25519".
+func (r *Ring) SignatureG(pk *big.Int, message []byte, signer int) (*GenericRingSignature, error) {
+	group, err := CurveByName(r.Curve)
+	if err != nil {
+		return nil, err
+	}
+	N := group.Order()
+
+	var messageHash [32]byte
+	copy(messageHash[:], message)
+	hashp := group.HashToPoint(messageHash)
+
+	pk = new(big.Int).Mod(pk, N)
+	hashSP := group.ScalarMult(hashp, pk)
+
+	hashAcc := sha256.Sum256(append(hashp.Marshal(), hashSP.Marshal()...))
+
+	n := len(r.PubKeys)
+	var ctlist []*big.Int
+	var a, b GroupElement
+	var ri *big.Int
+
+	csum := big.NewInt(0)
+
+	for j := 0; j < n; j++ {
+
+		if j != signer {
+			cj := group.RandomScalar()
+			tj := group.RandomScalar()
+
+			a = group.Add(group.ScalarBaseMult(tj), group.ScalarMult(r.PubKeys[j], cj))
+			b = group.Add(group.ScalarMult(hashp, tj), group.ScalarMult(hashSP, cj))
+
+			ctlist = append(ctlist, cj)
+			ctlist = append(ctlist, tj)
+			csum.Add(csum, cj)
+		}
+
+		if j == signer {
+			dummy := big.NewInt(0)
+			ctlist = append(ctlist, dummy)
+			ctlist = append(ctlist, dummy)
+			ri = group.RandomScalar()
+			a = group.ScalarBaseMult(ri)
+			b = group.ScalarMult(hashp, ri)
+		}
+
+		hashAcc = sha256.Sum256(append(hashAcc[:], append(a.Marshal(), b.Marshal()...)...))
+	}
+
+	hashb := new(big.Int).SetBytes(hashAcc[:])
+	hashb.Mod(hashb, N)
+
+	csum.Mod(csum, N)
+	c := new(big.Int).Sub(hashb, csum)
+	c.Mod(c, N)
+
+	cx := new(big.Int).Mul(c, pk)
+	cx.Mod(cx, N)
+	ti := new(big.Int).Sub(ri, cx)
+	ti.Mod(ti, N)
+	ctlist[2*signer] = c
+	ctlist[2*signer+1] = ti
+
+	return &GenericRingSignature{hashSP, ctlist}, nil
+}
+
+// VerifySignatureG is VerifySignature's Group-backed counterpart.
+func (r *Ring) VerifySignatureG(message []byte, sigma GenericRingSignature) (bool, error) {
+	group, err := CurveByName(r.Curve)
+	if err != nil {
+		return false, err
+	}
+	N := group.Order()
+
+	tau := sigma.Tau
+	ctlist := sigma.Ctlist
+	n := len(r.PubKeys)
+
+	var messageHash [32]byte
+	copy(messageHash[:], message)
+	hashp := group.HashToPoint(messageHash)
+
+	hashAcc := sha256.Sum256(append(hashp.Marshal(), tau.Marshal()...))
+
+	csum := big.NewInt(0)
+
+	for j := 0; j < n; j++ {
+		cj := new(big.Int).Mod(ctlist[2*j], N)
+		tj := new(big.Int).Mod(ctlist[2*j+1], N)
+
+		yc := group.ScalarMult(r.PubKeys[j], cj)
+		gt := group.Add(group.ScalarBaseMult(tj), yc)
+
+		tauc := group.ScalarMult(tau, cj)
+		H := group.Add(group.ScalarMult(hashp, tj), tauc)
+
+		hashAcc = sha256.Sum256(append(hashAcc[:], append(gt.Marshal(), H.Marshal()...)...))
+
+		csum.Add(csum, cj)
+		csum.Mod(csum, N)
+	}
+
+	hashout := new(big.Int).SetBytes(hashAcc[:])
+	hashout.Mod(hashout, N)
+
+	return csum.Cmp(hashout) == 0, nil
 }
@@ -4,34 +4,166 @@ package main
 
 import (
 	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"math/big"
 )
 
 // StealthAddress represents the stealth public key of another party
 type StealthAddress struct {
-	Public CurvePoint `json:"public"`
-	Nonce  *big.Int   `json:"nonce"`
+	Public GroupElement `json:"public"`
+	Nonce  *big.Int     `json:"nonce"`
 }
 
 // PrivateStealthAddress represents a stealth address that you own
 type PrivateStealthAddress struct {
-	Public  CurvePoint `json:"public"`
-	Nonce   *big.Int   `json:"nonce"`
-	Private *big.Int   `json:"private"`
+	Public  GroupElement `json:"public"`
+	Nonce   *big.Int     `json:"nonce"`
+	Private *big.Int     `json:"private"`
 }
 
 // StealthSession is used to communicate between two parties using
 // ephemeral key pairs for each message.
 //
+// Curve selects the Group backend ("secp256k1" or "ed25519") that
+// MyPublic, TheirPublic and every address below were derived with, so a
+// serialized session self-describes how to decode its points.
+//
 type StealthSession struct {
-	MyPublic       CurvePoint              `json:"myPublic"`
-	TheirPublic    CurvePoint              `json:"theirPublic"`
+	Curve          string                  `json:"curve"`
+	MyPublic       GroupElement            `json:"myPublic"`
+	TheirPublic    GroupElement            `json:"theirPublic"`
 	SharedSecret   []byte                  `json:"sharedSecret"`
 	TheirAddresses []StealthAddress        `json:"theirStealthAddresses"`
 	MyAddresses    []PrivateStealthAddress `json:"myStealthAddresses"`
 }
 
+// stealthAddressJSON, privateStealthAddressJSON and stealthSessionJSON are
+// the on-the-wire shapes of their counterparts above: GroupElement is an
+// interface, so it's marshaled as hex bytes and rebuilt against whichever
+// Group the session's Curve field names, the same trick Ring's hexBig
+// plays for *big.Int.
+type stealthAddressJSON struct {
+	Public hexBytes `json:"public"`
+	Nonce  *hexBig  `json:"nonce"`
+}
+
+type privateStealthAddressJSON struct {
+	Public  hexBytes `json:"public"`
+	Nonce   *hexBig  `json:"nonce"`
+	Private *hexBig  `json:"private"`
+}
+
+type stealthSessionJSON struct {
+	Curve          string                      `json:"curve"`
+	MyPublic       hexBytes                    `json:"myPublic"`
+	TheirPublic    hexBytes                    `json:"theirPublic"`
+	SharedSecret   []byte                      `json:"sharedSecret"`
+	TheirAddresses []stealthAddressJSON        `json:"theirStealthAddresses"`
+	MyAddresses    []privateStealthAddressJSON `json:"myStealthAddresses"`
+}
+
+// hexBytes JSON-encodes a []byte as a hex string, for GroupElement
+// marshaling where json's built-in base64 []byte encoding isn't what we
+// want to key off of.
+type hexBytes []byte
+
+// MarshalJSON converts hexBytes to a hex-encoded JSON string
+func (h hexBytes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(hex.EncodeToString(h))
+}
+
+// UnmarshalJSON converts a hex-encoded JSON string to hexBytes
+func (h *hexBytes) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	decoded, err := hex.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	*h = decoded
+	return nil
+}
+
+// MarshalJSON converts a StealthSession to its wire representation
+func (s StealthSession) MarshalJSON() ([]byte, error) {
+	group, err := CurveByName(s.Curve)
+	if err != nil {
+		return nil, err
+	}
+
+	theirAddresses := make([]stealthAddressJSON, len(s.TheirAddresses))
+	for i, a := range s.TheirAddresses {
+		theirAddresses[i] = stealthAddressJSON{hexBytes(group.Marshal(a.Public)), (*hexBig)(a.Nonce)}
+	}
+
+	myAddresses := make([]privateStealthAddressJSON, len(s.MyAddresses))
+	for i, a := range s.MyAddresses {
+		myAddresses[i] = privateStealthAddressJSON{hexBytes(group.Marshal(a.Public)), (*hexBig)(a.Nonce), (*hexBig)(a.Private)}
+	}
+
+	return json.Marshal(&stealthSessionJSON{
+		Curve:          s.Curve,
+		MyPublic:       hexBytes(group.Marshal(s.MyPublic)),
+		TheirPublic:    hexBytes(group.Marshal(s.TheirPublic)),
+		SharedSecret:   s.SharedSecret,
+		TheirAddresses: theirAddresses,
+		MyAddresses:    myAddresses,
+	})
+}
+
+// UnmarshalJSON converts a wire representation to a StealthSession,
+// decoding every point against the Group named by the Curve field
+func (s *StealthSession) UnmarshalJSON(data []byte) error {
+	var aux stealthSessionJSON
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	group, err := CurveByName(aux.Curve)
+	if err != nil {
+		return err
+	}
+
+	myPublic, err := group.Unmarshal(aux.MyPublic)
+	if err != nil {
+		return err
+	}
+	theirPublic, err := group.Unmarshal(aux.TheirPublic)
+	if err != nil {
+		return err
+	}
+
+	theirAddresses := make([]StealthAddress, len(aux.TheirAddresses))
+	for i, a := range aux.TheirAddresses {
+		pub, err := group.Unmarshal(a.Public)
+		if err != nil {
+			return err
+		}
+		theirAddresses[i] = StealthAddress{pub, (*big.Int)(a.Nonce)}
+	}
+
+	myAddresses := make([]PrivateStealthAddress, len(aux.MyAddresses))
+	for i, a := range aux.MyAddresses {
+		pub, err := group.Unmarshal(a.Public)
+		if err != nil {
+			return err
+		}
+		myAddresses[i] = PrivateStealthAddress{pub, (*big.Int)(a.Nonce), (*big.Int)(a.Private)}
+	}
+
+	s.Curve = aux.Curve
+	s.MyPublic = myPublic
+	s.TheirPublic = theirPublic
+	s.SharedSecret = aux.SharedSecret
+	s.TheirAddresses = theirAddresses
+	s.MyAddresses = myAddresses
+	return nil
+}
+
 // generateKeyPair generates a random secret key, then derives the
 // public key from it
 //
@@ -50,17 +182,28 @@ var bigTwo = new(big.Int).SetInt64(int64(2))
 // a valid curve point, where 0 < S < G
 //
 func isValidSecretKey(secret *big.Int) bool {
-	// secret < 1
+	return isValidSecretKeyG(secret, Secp256k1Group{})
+}
+
+// isValidSecretKeyG is isValidSecretKey's curve-agnostic form: it bounds
+// secret against whichever Group produced it instead of always assuming
+// secp256k1's order, which previously let every Ed25519 scalar through
+// only because edOrder happens to be smaller than secp256k1's N.
+// Ed25519Group's RandomScalar returns RFC 8032 clamped scalars, which by
+// construction sit above edOrder, so there's no equivalent "< order"
+// bound to check for it - non-zero and scalar-sized is all that's left
+// to verify.
+func isValidSecretKeyG(secret *big.Int, g Group) bool {
 	if secret.Cmp(bigOne) < 0 {
 		return false
 	}
 
-	// secret >= G
-	if secret.Cmp(CurvePoint{}.Order()) >= 0 {
-		return false
+	switch g.(type) {
+	case Secp256k1Group:
+		return secret.Cmp(g.Order()) < 0
+	default:
+		return secret.BitLen() <= 256
 	}
-
-	return true
 }
 
 // StealthPubDerive derives another parties Stealth Public Key (ssp) from
@@ -80,17 +223,31 @@ func StealthPubDerive(mpk *CurvePoint, secret []byte) *CurvePoint {
 		return nil
 	}
 
+	p, err := StealthPubDeriveG(Secp256k1Group{}, *mpk, secret)
+	if err != nil {
+		return nil
+	}
+	spk := p.(CurvePoint)
+	return &spk
+}
+
+// StealthPubDeriveG is the curve-agnostic form of StealthPubDerive: it
+// derives a stealth public key over whichever Group g implements, rather
+// than being hard-wired to the secp256k1 backend.
+//
+//   spk ← mpk + g^H(secret)
+//
+func StealthPubDeriveG(g Group, mpk GroupElement, secret []byte) (GroupElement, error) {
 	// X ← H(secret)
-	_hashout := sha256.Sum256(secret)
-	X := new(big.Int).SetBytes(_hashout[:])
+	hashout := sha256.Sum256(secret)
+	X := new(big.Int).SetBytes(hashout[:])
+	X.Mod(X, g.Order())
 
 	// Y ← g^X
-	Y := derivePublicKey(X)
+	Y := g.ScalarBaseMult(X)
 
 	// spk ← mpk + Y
-	spk := mpk.Add(Y)
-
-	return &spk
+	return g.Add(mpk, Y), nil
 }
 
 // StealthPrivDerive derives a Stealth Secret Key (ssk) from your
@@ -151,14 +308,46 @@ func deriveSharedSecret(myPriv *big.Int, theirPub *CurvePoint) []byte {
 	return theirPub.ScalarMult(myPriv).Marshal()[:32]
 }
 
+// deriveSharedSecretG is the curve-agnostic ECDH entry point: for the
+// secp256k1 backend it's the same point-multiply as deriveSharedSecret,
+// and for the ed25519 backend it runs X25519 proper. Either way it rejects
+// key reflection (theirPub == myPub), which a naive ECDH implementation
+// would otherwise happily "agree" on with itself.
+func deriveSharedSecretG(g Group, myPriv *big.Int, theirPub GroupElement) ([]byte, error) {
+	switch g := g.(type) {
+	case Ed25519Group:
+		return x25519SharedSecret(myPriv, theirPub.(Ed25519Point))
+	case Secp256k1Group:
+		myPub := g.ScalarBaseMult(myPriv)
+		if string(myPub.Marshal()) == string(theirPub.Marshal()) {
+			return nil, fmt.Errorf("Refusing to derive a shared secret with own public key")
+		}
+		return theirPub.(CurvePoint).ScalarMult(myPriv).Marshal()[:32], nil
+	default:
+		return nil, fmt.Errorf("Unsupported curve backend: %v", g.Name())
+	}
+}
+
 // NewStealthSession derives all information necessary to communicate between
-// two parties using a series of one-time key pairs.
+// two parties using a series of one-time key pairs, over the secp256k1
+// backend. Use NewStealthSessionG to pick a different Group.
 //
 func NewStealthSession(mySecret *big.Int, theirPublic *CurvePoint, nonceOffset int, addressCount int) (*StealthSession, error) {
+	if nil == theirPublic {
+		return nil, fmt.Errorf("Null public key provided")
+	}
+	return NewStealthSessionG(Secp256k1Group{}, mySecret, *theirPublic, nonceOffset, addressCount)
+}
+
+// NewStealthSessionG is the curve-agnostic form of NewStealthSession: g
+// selects the Group (and therefore the Curve recorded on the resulting
+// session) that every derivation below runs against.
+//
+func NewStealthSessionG(g Group, mySecret *big.Int, theirPublic GroupElement, nonceOffset int, addressCount int) (*StealthSession, error) {
 	var theirAddresses []StealthAddress
 	var myAddresses []PrivateStealthAddress
 
-	if false == isValidSecretKey(mySecret) {
+	if false == isValidSecretKeyG(mySecret, g) {
 		return nil, fmt.Errorf("Invalid secret key: %v", mySecret)
 	}
 
@@ -166,27 +355,32 @@ func NewStealthSession(mySecret *big.Int, theirPublic *CurvePoint, nonceOffset i
 		return nil, fmt.Errorf("Null public key provided")
 	}
 
-	sharedSecret := deriveSharedSecret(mySecret, theirPublic)
+	sharedSecret, err := deriveSharedSecretG(g, mySecret, theirPublic)
+	if err != nil {
+		return nil, err
+	}
+
 	for i := 0; i < addressCount; i++ {
 		nonce := new(big.Int).SetInt64(int64(nonceOffset + i))
 		secret := append(sharedSecret, nonce.Bytes()...)
 
-		theirStealthPub := StealthPubDerive(theirPublic, secret)
-		if theirStealthPub == nil {
-			return nil, fmt.Errorf("Could not derive stealth public key %v", i)
+		theirStealthPub, err := StealthPubDeriveG(g, theirPublic, secret)
+		if err != nil {
+			return nil, fmt.Errorf("Could not derive stealth public key %v: %v", i, err)
 		}
-		theirSA := StealthAddress{*theirStealthPub, nonce}
+		theirSA := StealthAddress{theirStealthPub, nonce}
 		theirAddresses = append(theirAddresses, theirSA)
 
-		myStealthPriv := StealthPrivDerive(mySecret, secret)
-		myStealthPub := derivePublicKey(myStealthPriv)
+		myStealthPriv := StealthPrivDeriveG(g, mySecret, secret)
+		myStealthPub := g.ScalarBaseMult(myStealthPriv)
 		mySA := PrivateStealthAddress{myStealthPub, nonce, myStealthPriv}
 		myAddresses = append(myAddresses, mySA)
 	}
 
 	session := StealthSession{
-		MyPublic:       derivePublicKey(mySecret),
-		TheirPublic:    *theirPublic,
+		Curve:          g.Name(),
+		MyPublic:       g.ScalarBaseMult(mySecret),
+		TheirPublic:    theirPublic,
 		SharedSecret:   sharedSecret,
 		TheirAddresses: theirAddresses,
 		MyAddresses:    myAddresses,
@@ -194,3 +388,16 @@ func NewStealthSession(mySecret *big.Int, theirPublic *CurvePoint, nonceOffset i
 
 	return &session, nil
 }
+
+// StealthPrivDeriveG is the curve-agnostic form of StealthPrivDerive.
+//
+//   ssk ← msk + H(secret)  (mod g.Order())
+//
+func StealthPrivDeriveG(g Group, msk *big.Int, secret []byte) *big.Int {
+	hashout := sha256.Sum256(secret)
+	X := new(big.Int).SetBytes(hashout[:])
+
+	ssk := new(big.Int).Add(msk, X)
+	ssk.Mod(ssk, g.Order())
+	return ssk
+}